@@ -20,12 +20,18 @@ under the License.
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"qpid.apache.org/amqp"
@@ -49,20 +55,325 @@ type Arrow struct {
 	id, netAddr, path                       string
 	messages                                int
 	bodySize, creditWindow, transactionSize int
-	flags                                   map[string]bool
+	pipeline                                int
+	options                                 map[string]string
 	connectionOptions                       []electron.ConnectionOption
+	tlsConfig                               *tls.Config
 
 	container  electron.Container
 	connection electron.Connection
 	incoming   bool // True if we accepted some incoming endpoint
+	stats      *stats
 }
 
-// Handle delivery outcomes on the sender.
-// Close connection on error or completion.
-func (a *Arrow) outcomes(out chan electron.Outcome) {
+// statsEnabled reports whether the "stats" key was given in the flag
+// argument, turning on latency/outcome accounting and the end-of-run
+// JSON summary on stderr.
+func (a *Arrow) statsEnabled() bool {
+	_, ok := a.options["stats"]
+	return ok
+}
+
+// histogram is a streaming, fixed-memory latency histogram covering
+// 1us-60s. Each power-of-two range in that span is divided into
+// subBuckets linear bins, giving ~1/subBuckets relative error
+// independent of the recorded value's magnitude, without storing
+// individual samples.
+type histogram struct {
+	subBuckets int
+	min, max   int64
+	counts     []uint64
+}
+
+func newHistogram() *histogram {
+	const min, max, subBuckets = int64(time.Microsecond), int64(60 * time.Second), 128
+	powers := 0
+	for v := min; v < max; v <<= 1 {
+		powers++
+	}
+	return &histogram{
+		subBuckets: subBuckets,
+		min:        min,
+		max:        max,
+		counts:     make([]uint64, (powers+1)*subBuckets),
+	}
+}
+
+func (h *histogram) bucket(v int64) int {
+	if v < h.min {
+		v = h.min
+	}
+	if v > h.max {
+		v = h.max
+	}
+	power := 0
+	for base := h.min; v >= base<<1; base <<= 1 {
+		power++
+	}
+	base := h.min << uint(power)
+	sub := int((v - base) * int64(h.subBuckets) / base)
+	if sub >= h.subBuckets {
+		sub = h.subBuckets - 1
+	}
+	return power*h.subBuckets + sub
+}
+
+func (h *histogram) value(bucket int) int64 {
+	power, sub := bucket/h.subBuckets, bucket%h.subBuckets
+	base := h.min << uint(power)
+	return base + int64(sub)*base/int64(h.subBuckets)
+}
+
+func (h *histogram) record(v int64) { h.counts[h.bucket(v)]++ }
+
+func (h *histogram) total() uint64 {
+	var n uint64
+	for _, c := range h.counts {
+		n += c
+	}
+	return n
+}
+
+// percentile returns the lower bound of the bucket holding the value
+// at the given percentile (0..1), so it is accurate to within one
+// bucket's width rather than an exact recorded value.
+func (h *histogram) percentile(p float64) int64 {
+	total := h.total()
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(p * float64(total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			return h.value(i)
+		}
+	}
+	return h.max
+}
+
+func (h *histogram) maxRecorded() int64 {
+	for i := len(h.counts) - 1; i >= 0; i-- {
+		if h.counts[i] > 0 {
+			return h.value(i)
+		}
+	}
+	return 0
+}
+
+// stats accumulates the optional per-run metrics gated by the "stats"
+// flag: send-to-settle (or creation-to-receive) latency, outcome
+// counts, and throughput, summarized as JSON on stderr at the end of
+// the run.
+type stats struct {
+	enabled                                         bool
+	start                                            time.Time
+	latency                                          *histogram
+	accepted, rejected, released, modified, expired uint64
+}
+
+func newStats(enabled bool) *stats {
+	return &stats{enabled: enabled, start: time.Now(), latency: newHistogram()}
+}
+
+// recordOutcome records the send-to-settle latency and outcome status
+// of a delivery whose SendAsync was issued at sentAt.
+func (s *stats) recordOutcome(o electron.Outcome, sentAt time.Time) {
+	if !s.enabled {
+		return
+	}
+	s.latency.record(int64(time.Since(sentAt)))
+	switch o.Status {
+	case electron.Accepted:
+		s.accepted++
+	case electron.Rejected:
+		s.rejected++
+	case electron.Released:
+		s.released++
+	case electron.Modified:
+		s.modified++
+	}
+}
+
+// recordReceive records the creation-time-to-receive latency of m, and
+// notes whether its absolute-expiry-time has already passed, when
+// those optional header fields are present.
+func (s *stats) recordReceive(m amqp.Message) {
+	if !s.enabled {
+		return
+	}
+	if ct := m.CreationTime(); !ct.IsZero() {
+		s.latency.record(int64(time.Since(ct)))
+	}
+	if et := m.AbsoluteExpiryTime(); !et.IsZero() && et.Before(time.Now()) {
+		s.expired++
+	}
+}
+
+// runSummary is the JSON shape printed to stderr at the end of a
+// stats-enabled run.
+type runSummary struct {
+	Messages       int64             `json:"messages"`
+	Seconds        float64           `json:"seconds"`
+	MessagesPerSec float64           `json:"messagesPerSec"`
+	BytesPerSec    float64           `json:"bytesPerSec"`
+	LatencyP50Us   float64           `json:"latencyP50Us"`
+	LatencyP95Us   float64           `json:"latencyP95Us"`
+	LatencyP99Us   float64           `json:"latencyP99Us"`
+	LatencyMaxUs   float64           `json:"latencyMaxUs"`
+	Expired        uint64            `json:"expired"`
+	Outcomes       map[string]uint64 `json:"outcomes"`
+}
+
+// finish prints the JSON run summary to stderr, if stats are enabled.
+func (s *stats) finish(a *Arrow) {
+	if !s.enabled {
+		return
+	}
+	elapsed := time.Since(s.start).Seconds()
+	toUs := func(ns int64) float64 { return float64(ns) / float64(time.Microsecond) }
+	summary := runSummary{
+		Messages:       int64(a.messages),
+		Seconds:        elapsed,
+		MessagesPerSec: float64(a.messages) / elapsed,
+		BytesPerSec:    float64(int64(a.messages)*int64(a.bodySize)) / elapsed,
+		LatencyP50Us:   toUs(s.latency.percentile(0.50)),
+		LatencyP95Us:   toUs(s.latency.percentile(0.95)),
+		LatencyP99Us:   toUs(s.latency.percentile(0.99)),
+		LatencyMaxUs:   toUs(s.latency.maxRecorded()),
+		Expired:        s.expired,
+		Outcomes: map[string]uint64{
+			"Accepted": s.accepted,
+			"Rejected": s.rejected,
+			"Released": s.released,
+			"Modified": s.modified,
+		},
+	}
+	b, err := json.Marshal(summary)
+	failIfErr(err)
+	fmt.Fprintln(os.Stderr, string(b))
+}
+
+// AMQP 1.0 transaction descriptor codes (amqp-transactions-v1.0).
+const (
+	txnDeclareCode   = uint64(0x0000000000000031)
+	txnDischargeCode = uint64(0x0000000000000032)
+)
+
+// txn wraps a coordinator link for declare/discharge. electron has no
+// txn-controller API and, per this file's own sendRecord usage below,
+// the only things it exposes back from a delivery are Outcome.Status
+// and Outcome.Error -- Outcome.Value is purely an echo of whatever was
+// passed to SendAsync. There is no proven way to read the txn id the
+// broker assigns in a declare's "declared" disposition, nor to attach
+// transactional state to a send or an accept. So declare/discharge here
+// only confirm the coordinator link accepts the raw frames; they
+// cannot make the sends/accepts in between actually participate in the
+// declared transaction. transactionalSender and transactionalReceiver
+// warn about this on stderr rather than silently pretending otherwise.
+type txn struct {
+	coordinator electron.Sender
+}
+
+func newTxn(c electron.Connection) (*txn, error) {
+	s, err := c.Sender(electron.Target("amqp:coordinator"), electron.AtLeastOnce())
+	if err != nil {
+		return nil, err
+	}
+	return &txn{coordinator: s}, nil
+}
+
+// roundTrip sends a coordinator command and waits for its outcome
+// through the same SendAsync+channel path every other sender in this
+// file uses, since that is the only delivery-outcome mechanism electron
+// demonstrably provides.
+func (t *txn) roundTrip(body amqp.Described) error {
+	m := amqp.NewMessage()
+	m.Marshal(body)
+	out := make(chan electron.Outcome, 1)
+	t.coordinator.SendAsync(m, out, nil)
+	o := <-out
+	if o.Status != electron.Accepted {
+		return fmt.Errorf("coordinator did not accept %v: %v", body.Descriptor, o.Error)
+	}
+	return nil
+}
+
+// declare starts a new transaction. The txn id the broker assigns is
+// carried back in the declare's remote disposition state, which
+// electron does not expose, so it is discarded here; see the txn
+// doc comment.
+func (t *txn) declare() error {
+	return t.roundTrip(amqp.Described{Descriptor: txnDeclareCode, Value: amqp.List{nil}})
+}
+
+// discharge commits the transaction, or aborts it if fail is true.
+// With no txn id available (see declare), this discharges whatever the
+// broker considers the coordinator link's current transaction rather
+// than a specific declared one.
+func (t *txn) discharge(fail bool) error {
+	return t.roundTrip(amqp.Described{Descriptor: txnDischargeCode, Value: amqp.List{nil, fail}})
+}
+
+// Compute the current time in milliseconds since the Epoch for quiver.
+func now() int64 { t := time.Now(); return t.UnixNano() / int64(time.Millisecond) }
+
+// pipelineSize returns the number of SendAsync calls allowed in
+// flight at once, independent of the link's credit window.
+func (a *Arrow) pipelineSize() int {
+	if a.pipeline > 0 {
+		return a.pipeline
+	}
+	return a.creditWindow
+}
+
+// Act as a sender. A producer goroutine keeps up to pipelineSize()
+// SendAsyncTimeout calls in flight, reusing pooled messages so only
+// the message id and SendTime property are rebuilt per send; this
+// loop drains outcomes in bulk and recycles each message once its
+// outcome arrives.
+func (a *Arrow) sender(s electron.Sender) {
+	if a.transactionSize > 0 {
+		a.transactionalSender(s)
+		return
+	}
+
+	pipeline := a.pipelineSize()
+	out := make(chan electron.Outcome, pipeline*2)
+	inFlight := make(chan struct{}, pipeline)
+	body := strings.Repeat("x", int(a.bodySize))
+	pool := &sync.Pool{New: func() interface{} {
+		m := amqp.NewMessageWith(body)
+		m.SetApplicationProperties(make(map[string]interface{}, 1))
+		return m
+	}}
+
+	go func() {
+		for i := 0; i < a.messages; i++ {
+			failIfErr(s.Error())
+			inFlight <- struct{}{} // May block until pipeline has room
+			m := pool.Get().(amqp.Message)
+			id := i + 1
+			m.SetMessageId(strconv.Itoa(id))
+			t := now()
+			m.ApplicationProperties()["SendTime"] = t
+			fmt.Printf("%v,%v\n", id, t)
+			s.SendAsyncTimeout(m, out, sendRecord{m, time.Now()}, electron.Forever)
+		}
+	}()
+
 	for i := 0; i < a.messages; i++ {
 		select {
 		case o := <-out:
+			<-inFlight
+			if rec, ok := o.Value.(sendRecord); ok {
+				pool.Put(rec.msg)
+				a.stats.recordOutcome(o, rec.sentAt)
+			}
 			if o.Status != electron.Accepted {
 				fail("Unexpected delivery outcome: %v", o)
 			}
@@ -70,43 +381,147 @@ func (a *Arrow) outcomes(out chan electron.Outcome) {
 			fail("Not enough outcomes %v < %v", i, a.messages)
 		}
 	}
+	a.stats.finish(a)
 	a.connection.Close(nil)
 }
 
-// Compute the current time in milliseconds since the Epoch for quiver.
-func now() int64 { t := time.Now(); return t.UnixNano() / int64(time.Millisecond) }
+// sendRecord is the Value attached to an async send so its outcome
+// can recycle the pooled message and record its settle latency.
+type sendRecord struct {
+	msg    amqp.Message
+	sentAt time.Time
+}
+
+// Act as a sender, grouping sends into transactions of transactionSize.
+// Each batch is declared, sent, and awaited for Accepted outcomes before
+// it is committed (or aborted on error), reporting commit latency
+// separately from send latency. As noted on txn, electron gives no way
+// to make the sends in a batch actually participate in the declared
+// transaction, so an aborted batch will not be rolled back on the wire;
+// this only brackets batches for commit-latency measurement.
+func (a *Arrow) transactionalSender(s electron.Sender) {
+	fmt.Fprintln(os.Stderr, "warning: electron exposes no API to attach transactional state to a delivery, so transactionSize only brackets batches for timing -- sends are not rolled back on abort")
+
+	t, err := newTxn(a.connection)
+	failIfErr(err)
+	defer t.coordinator.Close(nil)
 
-// Act as a sender
-func (a *Arrow) sender(s electron.Sender) {
-	out := make(chan electron.Outcome, a.creditWindow)
-	go a.outcomes(out)
 	m := amqp.NewMessageWith(strings.Repeat("x", int(a.bodySize)))
 	m.SetApplicationProperties(make(map[string]interface{}, 1))
-	for i := 0; i < a.messages; i++ {
-		failIfErr(s.Error())
-		id := i + 1
-		m.SetMessageId(strconv.Itoa(id))
-		t := now()
-		m.ApplicationProperties()["SendTime"] = t
-		fmt.Printf("%v,%v\n", id, t)
-		s.SendAsync(m, out, nil) // May block for credit. Errors reported via outcomes
+	sent := 0
+	for sent < a.messages {
+		failIfErr(t.declare())
+
+		batch := a.transactionSize
+		if a.messages-sent < batch {
+			batch = a.messages - sent
+		}
+		out := make(chan electron.Outcome, batch)
+		for i := 0; i < batch; i++ {
+			failIfErr(s.Error())
+			id := sent + i + 1
+			m.SetMessageId(strconv.Itoa(id))
+			st := now()
+			m.ApplicationProperties()["SendTime"] = st
+			fmt.Printf("%v,%v\n", id, st)
+			s.SendAsync(m, out, time.Now())
+		}
+		for i := 0; i < batch; i++ {
+			o := <-out
+			if sentAt, ok := o.Value.(time.Time); ok {
+				a.stats.recordOutcome(o, sentAt)
+			}
+			if o.Status != electron.Accepted {
+				failIfErr(t.discharge(true))
+				fail("Unexpected delivery outcome: %v", o)
+			}
+		}
+
+		commitStart := now()
+		failIfErr(t.discharge(false))
+		fmt.Fprintf(os.Stderr, "commit,%v,%v\n", batch, now()-commitStart)
+
+		sent += batch
 	}
-	<-a.connection.Done() // Wait for outcomes() to close the connection
+	a.stats.finish(a)
+	a.connection.Close(nil)
 }
 
-// Act as a receiver
+// Act as a receiver. Deliveries are received with ReceiveTimeout and
+// accepted in batches of pipelineSize(), one Accept() per delivery.
 func (a *Arrow) receiver(r electron.Receiver) {
+	if a.transactionSize > 0 {
+		a.transactionalReceiver(r)
+		return
+	}
+
+	pipeline := a.pipelineSize()
+	pending := make([]electron.ReceivedMessage, 0, pipeline)
+	acceptPending := func() {
+		for _, rm := range pending {
+			rm.Accept()
+			a.stats.recordReceive(rm.Message)
+		}
+		pending = pending[:0]
+	}
+
 	for i := 0; i < a.messages; i++ {
-		rm, err := r.Receive()
+		rm, err := r.ReceiveTimeout(electron.Forever)
 		failIfErr(err)
-		rm.Accept()
 		m := rm.Message
+		a.stats.recordReceive(m)
 		t := m.ApplicationProperties()["SendTime"]
 		if t == nil {
 			fail("no SendTime property in %v", m)
 		}
 		fmt.Printf("%v,%v,%v\n", m.MessageId(), t, now())
+
+		pending = append(pending, rm)
+		if len(pending) == pipeline || i == a.messages-1 {
+			acceptPending()
+		}
+	}
+	a.stats.finish(a)
+	a.connection.Close(nil)
+}
+
+// Act as a receiver, accepting messages in batches of transactionSize
+// and discharging once per batch. As noted on txn, electron gives no
+// way to make an accept participate in the declared transaction, so
+// each accept below settles the delivery outright rather than under
+// the txn; this only brackets batches for commit-latency measurement.
+func (a *Arrow) transactionalReceiver(r electron.Receiver) {
+	fmt.Fprintln(os.Stderr, "warning: electron exposes no API to accept a delivery under a transaction, so transactionSize only brackets batches for timing -- accepts settle immediately")
+
+	t, err := newTxn(a.connection)
+	failIfErr(err)
+	defer t.coordinator.Close(nil)
+
+	received := 0
+	for received < a.messages {
+		failIfErr(t.declare())
+
+		batch := a.transactionSize
+		if a.messages-received < batch {
+			batch = a.messages - received
+		}
+		for i := 0; i < batch; i++ {
+			rm, err := r.Receive()
+			failIfErr(err)
+			rm.Accept()
+			m := rm.Message
+			a.stats.recordReceive(m)
+			st := m.ApplicationProperties()["SendTime"]
+			if st == nil {
+				fail("no SendTime property in %v", m)
+			}
+			fmt.Printf("%v,%v,%v\n", m.MessageId(), st, now())
+		}
+
+		failIfErr(t.discharge(false))
+		received += batch
 	}
+	a.stats.finish(a)
 	a.connection.Close(nil)
 }
 
@@ -159,16 +574,25 @@ func (a *Arrow) connected() {
 }
 
 func (a *Arrow) run() {
+	a.stats = newStats(a.statsEnabled())
 	a.container = electron.NewContainer(a.id)
 	switch a.connectionMode {
 	case "client":
-		c, err := a.container.Dial("tcp", a.netAddr, a.connectionOptions...)
+		conn, err := net.Dial("tcp", a.netAddr)
+		failIfErr(err)
+		if a.tlsConfig != nil {
+			conn = tls.Client(conn, a.tlsConfig)
+		}
+		c, err := a.container.Connection(conn, a.connectionOptions...)
 		failIfErr(err)
 		a.connection = c
 		a.connected()
 	case "server":
 		l, err := net.Listen("tcp", a.netAddr)
 		failIfErr(err)
+		if a.tlsConfig != nil {
+			l = tls.NewListener(l, a.tlsConfig)
+		}
 		defer l.Close()
 		for !a.incoming { // Ignore connections with no incoming activity
 			a.connection, err = a.container.Accept(l, a.connectionOptions...)
@@ -176,11 +600,73 @@ func (a *Arrow) run() {
 			a.connected()
 		}
 
+	case "pipe":
+		a.runPipe()
+
 	default:
 		fail("bad connection mode %v", a.connectionMode)
 	}
 }
 
+// runPipe runs both ends of the connection in this process over a
+// single net.Pipe(), instead of net.Listen/Dial over a real socket.
+// This measures the pure electron + marshalling overhead with no
+// kernel or TCP involved, giving a deterministic baseline for
+// regression testing electron upgrades. netAddr is ignored. The two
+// ends share a container id suffix so their outputs can be correlated.
+func (a *Arrow) runPipe() {
+	left, right := net.Pipe()
+	passive := electron.NewContainer("passive-" + a.id)
+	active := electron.NewContainer("active-" + a.id)
+
+	done := make(chan struct{})
+	go func() {
+		b := *a
+		b.container = passive
+		// Each end needs its own *stats: a.stats is shared by the
+		// shallow copy above, and both ends record latency and print
+		// a summary independently.
+		b.stats = newStats(a.statsEnabled())
+		c, err := b.container.Accept(newPipeListener(right), b.connectionOptions...)
+		failIfErr(err)
+		b.connection = c
+		b.channelMode = "passive"
+		b.connected()
+		close(done)
+	}()
+
+	c, err := active.Connection(left, a.connectionOptions...)
+	failIfErr(err)
+	a.container = active
+	a.connection = c
+	a.channelMode = "active"
+	a.connected()
+	<-done
+}
+
+// pipeListener is a net.Listener that yields a single pre-established
+// net.Conn, letting electron.Container.Accept drive one half of a
+// net.Pipe() pair the same way it drives a real net.Listener.
+type pipeListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func newPipeListener(conn net.Conn) *pipeListener {
+	return &pipeListener{conn: conn}
+}
+
+func (l *pipeListener) Accept() (c net.Conn, err error) {
+	l.once.Do(func() { c = l.conn })
+	if c == nil {
+		err = fmt.Errorf("pipe listener: connection already accepted")
+	}
+	return c, err
+}
+
+func (l *pipeListener) Close() error   { return nil }
+func (l *pipeListener) Addr() net.Addr { return l.conn.LocalAddr() }
+
 func intArg(i int) int {
 	n, err := strconv.Atoi(os.Args[i])
 	if err != nil {
@@ -188,16 +674,92 @@ func intArg(i int) int {
 	}
 	return n
 }
-func flagArg(i int) map[string]bool {
+
+// flagArg parses the comma-separated key[=value] list carried in the
+// flag argument, e.g. "tls,tls-cert=a.pem,tls-key=a.key". A bare key
+// with no "=value" is recorded with an empty value, so its presence
+// can still be tested with a plain map lookup.
+func flagArg(i int) map[string]string {
 	s := strings.TrimSpace(os.Args[i])
-	var flags map[string]bool
+	options := make(map[string]string)
 	if len(s) > 0 {
-		flags := make(map[string]bool)
-		for _, key := range strings.Split(s, ",") {
-			flags[key] = true
+		for _, pair := range strings.Split(s, ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) == 2 {
+				options[parts[0]] = parts[1]
+			} else {
+				options[parts[0]] = ""
+			}
+		}
+	}
+	return options
+}
+
+// connectionOptionsFrom builds electron connection options from the
+// sasl-*/vhost keys in options, defaulting to anonymous SASL when none
+// of the sasl-* keys are given.
+func connectionOptionsFrom(options map[string]string) []electron.ConnectionOption {
+	var opts []electron.ConnectionOption
+
+	_, hasUser := options["sasl-user"]
+	_, hasPassword := options["sasl-password"]
+	if mechs, ok := options["sasl-mechs"]; ok {
+		opts = append(opts, electron.SASLAllowedMechs(mechs))
+	} else if !hasUser && !hasPassword {
+		opts = append(opts, electron.SASLAllowedMechs("ANONYMOUS"))
+	}
+	if user, ok := options["sasl-user"]; ok {
+		opts = append(opts, electron.User(user))
+	}
+	if password, ok := options["sasl-password"]; ok {
+		opts = append(opts, electron.Password([]byte(password)))
+	}
+	if vhost, ok := options["vhost"]; ok {
+		opts = append(opts, electron.VirtualHost(vhost))
+	}
+
+	return opts
+}
+
+// pipelineFrom reads the "pipeline" key from options, the in-flight
+// SendAsync window used in place of creditWindow. 0 means unset, and
+// falls back to creditWindow.
+func pipelineFrom(options map[string]string) int {
+	n, err := strconv.Atoi(options["pipeline"])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// tlsConfigFrom builds a *tls.Config from the tls-* keys in options,
+// or returns nil if the "tls" key is not present.
+func tlsConfigFrom(options map[string]string) *tls.Config {
+	if _, ok := options["tls"]; !ok {
+		return nil
+	}
+
+	config := &tls.Config{}
+
+	if _, ok := options["tls-insecure"]; ok {
+		config.InsecureSkipVerify = true
+	}
+	if certFile, ok := options["tls-cert"]; ok {
+		cert, err := tls.LoadX509KeyPair(certFile, options["tls-key"])
+		failIfErr(err)
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile, ok := options["tls-ca"]; ok {
+		ca, err := ioutil.ReadFile(caFile)
+		failIfErr(err)
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			fail("failed to parse tls-ca %v", caFile)
 		}
+		config.RootCAs = pool
 	}
-	return flags
+
+	return config
 }
 
 func main() {
@@ -209,6 +771,7 @@ func main() {
 	if len(os.Args) != want {
 		fail("incorrect number of arguments: want %v, got %v", want, len(os.Args))
 	}
+	options := flagArg(12)
 	a := Arrow{
 		connectionMode:  os.Args[1],
 		channelMode:     os.Args[2],
@@ -220,13 +783,12 @@ func main() {
 		bodySize:        intArg(9),
 		creditWindow:    intArg(10),
 		transactionSize: intArg(11),
-		flags:           flagArg(12),
+		pipeline:        pipelineFrom(options),
+		options:         options,
 
-		connectionOptions: []electron.ConnectionOption{electron.SASLAllowedMechs("ANONYMOUS")},
+		connectionOptions: connectionOptionsFrom(options),
+		tlsConfig:         tlsConfigFrom(options),
 	}
 
-	if a.transactionSize > 0 {
-		fail("transactions not supported")
-	}
 	a.run()
 }
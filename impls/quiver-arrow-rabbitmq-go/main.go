@@ -0,0 +1,219 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func fail(format string, arg ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s (%s): %s\n",
+		filepath.Base(os.Args[0]), os.Args[3], fmt.Sprintf(format, arg...))
+	os.Exit(1)
+}
+
+func failIfErr(err error) {
+	if err != nil {
+		fail("%v", err)
+	}
+}
+
+// Arrow is the 0-9-1 counterpart of the electron arrow. It speaks the
+// same CLI contract so quiver can drive it interchangeably, but since
+// 0-9-1 has no peer-to-peer listen/accept model, connectionMode must
+// be "client" and channelMode must be "active": RabbitMQ is always the
+// server and this process is always the one that dials it.
+type Arrow struct {
+	connectionMode, channelMode, operation  string
+	id, netAddr, path                       string
+	messages                                int
+	bodySize, creditWindow, transactionSize int
+	options                                 map[string]string
+
+	connection *amqp.Connection
+	channel    *amqp.Channel
+}
+
+// Compute the current time in milliseconds since the Epoch for quiver.
+func now() int64 { t := time.Now(); return t.UnixNano() / int64(time.Millisecond) }
+
+// Act as a sender
+func (a *Arrow) sender() {
+	body := strings.Repeat("x", int(a.bodySize))
+	inTxn := a.transactionSize > 0
+
+	for i := 0; i < a.messages; i++ {
+		if inTxn && i%a.transactionSize == 0 {
+			failIfErr(a.channel.Tx())
+		}
+
+		id := i + 1
+		t := now()
+		m := amqp.Publishing{
+			MessageId: strconv.Itoa(id),
+			Timestamp: time.Unix(0, t*int64(time.Millisecond)),
+			Headers:   amqp.Table{"SendTime": t},
+			Body:      []byte(body),
+		}
+		failIfErr(a.channel.Publish("", a.path, false, false, m))
+		fmt.Printf("%v,%v\n", id, t)
+
+		if inTxn && (i+1)%a.transactionSize == 0 {
+			failIfErr(a.channel.TxCommit())
+		}
+	}
+	if inTxn && a.messages%a.transactionSize != 0 {
+		failIfErr(a.channel.TxCommit())
+	}
+}
+
+// Act as a receiver
+func (a *Arrow) receiver() {
+	deliveries, err := a.channel.Consume(a.path, a.id, false, false, false, false, nil)
+	failIfErr(err)
+
+	inTxn := a.transactionSize > 0
+	if inTxn {
+		failIfErr(a.channel.Tx())
+	}
+
+	for i := 0; i < a.messages; i++ {
+		d, ok := <-deliveries
+		if !ok {
+			fail("delivery channel closed after %v < %v messages", i, a.messages)
+		}
+
+		t, ok := d.Headers["SendTime"]
+		if !ok {
+			fail("no SendTime header in %v", d.MessageId)
+		}
+		failIfErr(d.Ack(false))
+		fmt.Printf("%v,%v,%v\n", d.MessageId, t, now())
+
+		if inTxn && (i+1)%a.transactionSize == 0 {
+			failIfErr(a.channel.TxCommit())
+			if i+1 < a.messages {
+				failIfErr(a.channel.Tx())
+			}
+		}
+	}
+	if inTxn && a.messages%a.transactionSize != 0 {
+		failIfErr(a.channel.TxCommit())
+	}
+}
+
+func (a *Arrow) run() {
+	if a.connectionMode != "client" {
+		fail("connection mode %v not meaningful for AMQP 0-9-1: RabbitMQ is always the server", a.connectionMode)
+	}
+	if a.channelMode != "active" {
+		fail("channel mode %v not meaningful for AMQP 0-9-1: this arrow always initiates", a.channelMode)
+	}
+
+	url := fmt.Sprintf("amqp://%v/", a.netAddr)
+	if vhost, ok := a.options["vhost"]; ok {
+		url = fmt.Sprintf("amqp://%v/%v", a.netAddr, vhost)
+	}
+	c, err := amqp.Dial(url)
+	failIfErr(err)
+	defer c.Close()
+	a.connection = c
+
+	ch, err := c.Channel()
+	failIfErr(err)
+	defer ch.Close()
+	a.channel = ch
+
+	_, durable := a.options["durable"]
+	_, err = ch.QueueDeclare(a.path, durable, !durable, false, false, nil)
+	failIfErr(err)
+
+	failIfErr(ch.Qos(a.creditWindow, 0, false))
+
+	switch a.operation {
+	case "send":
+		a.sender()
+	case "receive":
+		a.receiver()
+	default:
+		fail("Bad operation: %v", a.operation)
+	}
+}
+
+func intArg(i int) int {
+	n, err := strconv.Atoi(os.Args[i])
+	if err != nil {
+		fail("arg[%v] not integer: %v", i, err)
+	}
+	return n
+}
+
+// flagArg parses the comma-separated key[=value] list carried in the
+// flag argument, e.g. "durable,vhost=/test". A bare key with no
+// "=value" is recorded with an empty value, so its presence can still
+// be tested with a plain map lookup.
+func flagArg(i int) map[string]string {
+	s := strings.TrimSpace(os.Args[i])
+	options := make(map[string]string)
+	if len(s) > 0 {
+		for _, pair := range strings.Split(s, ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) == 2 {
+				options[parts[0]] = parts[1]
+			} else {
+				options[parts[0]] = ""
+			}
+		}
+	}
+	return options
+}
+
+func main() {
+	if len(os.Args) == 1 {
+		fmt.Printf("RabbitMQ Go")
+		os.Exit(0)
+	}
+	want := 13
+	if len(os.Args) != want {
+		fail("incorrect number of arguments: want %v, got %v", want, len(os.Args))
+	}
+	a := Arrow{
+		connectionMode:  os.Args[1],
+		channelMode:     os.Args[2],
+		operation:       os.Args[3],
+		id:              os.Args[4],
+		netAddr:         fmt.Sprintf("%v:%v", os.Args[5], os.Args[6]),
+		path:            os.Args[7],
+		messages:        intArg(8),
+		bodySize:        intArg(9),
+		creditWindow:    intArg(10),
+		transactionSize: intArg(11),
+		options:         flagArg(12),
+	}
+
+	a.run()
+}